@@ -0,0 +1,33 @@
+// Package config holds tunables for synthetic tests that need per-release or per-platform
+// overrides without requiring a recompile.
+package config
+
+import "time"
+
+// StaticPodBudgets is the maximum time a static pod is allowed to take to recover from a
+// lifecycle failure before the recovery is considered too slow, keyed by operand namespace.
+// Recoveries within budget are reported as flakes; recoveries beyond it are reported as a
+// separate failure even though the static pod did eventually come up.
+type StaticPodBudgets struct {
+	// Default is used for any namespace without a more specific entry in PerNamespace.
+	Default time.Duration
+	// PerNamespace overrides Default for specific operand namespaces.
+	PerNamespace map[string]time.Duration
+}
+
+// DefaultStaticPodBudgets is the budget used when no override has been configured: 5 minutes for
+// kube-apiserver, kube-controller-manager and kube-scheduler, 10 minutes for etcd.
+var DefaultStaticPodBudgets = StaticPodBudgets{
+	Default: 5 * time.Minute,
+	PerNamespace: map[string]time.Duration{
+		"openshift-etcd": 10 * time.Minute,
+	},
+}
+
+// Budget returns the recovery budget configured for namespace, falling back to Default.
+func (b StaticPodBudgets) Budget(namespace string) time.Duration {
+	if d, ok := b.PerNamespace[namespace]; ok {
+		return d
+	}
+	return b.Default
+}
@@ -0,0 +1,19 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestStaticPodBudgetsBudget uses the operand namespaces a StaticPodFailure actually carries (the
+// "in namespace: ..." value parsed out of the failure message, e.g. "openshift-etcd"), not the
+// operator namespaces the failure's events are listed from, since that's the value the real call
+// site in synthetictests passes to Budget.
+func TestStaticPodBudgetsBudget(t *testing.T) {
+	if got := DefaultStaticPodBudgets.Budget("openshift-etcd"); got != 10*time.Minute {
+		t.Errorf("expected etcd budget of 10m, got %s", got)
+	}
+	if got := DefaultStaticPodBudgets.Budget("openshift-kube-apiserver"); got != 5*time.Minute {
+		t.Errorf("expected default budget of 5m, got %s", got)
+	}
+}
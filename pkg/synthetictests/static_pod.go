@@ -2,55 +2,80 @@ package synthetictests
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"regexp"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	configv1 "github.com/openshift/api/config/v1"
+	configclient "github.com/openshift/client-go/config/clientset/versioned"
 	"github.com/openshift/origin/pkg/monitor/monitorapi"
+	"github.com/openshift/origin/pkg/synthetictests/config"
+	"github.com/openshift/origin/pkg/synthetictests/staticpods"
 	"github.com/openshift/origin/pkg/test/ginkgo/junitapi"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
-// staticPodFailureRegex trying to pull out information from messages like
-// `static pod lifecycle failure - static pod: "etcd" in namespace: "openshift-etcd" for revision: 6 on node: "ovirt10-gh8t5-master-2" didn't show up, waited: 2m30s`
-var staticPodFailureRegex = regexp.MustCompile(
-	`static pod lifecycle failure - static pod: ".*" in namespace: "(.*)" for revision: (\d) on node: "(.*)" didn't show up, waited: .*`)
+// lastKnownGoodRevisionAnnotation is set on a static pod manifest by the installer when it has
+// fallen back to the last-known-good revision after the intended revision failed to come up.
+// Nothing in this file reads it: fallbacks are detected only from the installer's
+// StaticPodFallbackRevisionSucceeded/Failed events, and only on single node topologies.
+const lastKnownGoodRevisionAnnotation = "openshift.io/last-known-good-revision"
+
+// staticPodCollector is shared by every invocation of testStaticPodLifecycleFailure (and, in time,
+// by the equivalent etcd/KCM/KAS/scheduler synthetic tests) so that the cluster's static pod event
+// history is only ever listed and watched once per run, rather than re-listed per test.
+var (
+	staticPodCollectorOnce sync.Once
+	staticPodCollector     *staticpods.Collector
+	staticPodCollectorErr  error
+)
 
-type staticPodFailure struct {
-	namespace      string
-	node           string
-	revision       int64
-	failureMessage string
-}
+func getStaticPodCollector(kubeClientConfig *rest.Config) (*staticpods.Collector, error) {
+	staticPodCollectorOnce.Do(func() {
+		ctx := context.Background()
 
-func staticPodFailureFromMessage(message string) (*staticPodFailure, error) {
-	matches := staticPodFailureRegex.FindStringSubmatch(message)
-	if len(matches) != 4 {
-		return nil, fmt.Errorf("wrong number of matches: %v", matches)
-	}
-	revision, err := strconv.ParseInt(matches[2], 0, 64)
-	if err != nil {
-		return nil, err
-	}
+		kubeClient, err := kubernetes.NewForConfig(kubeClientConfig)
+		if err != nil {
+			staticPodCollectorErr = err
+			return
+		}
+		dynamicClient, err := dynamic.NewForConfig(kubeClientConfig)
+		if err != nil {
+			staticPodCollectorErr = err
+			return
+		}
+		discoveryClient, err := discovery.NewDiscoveryClientForConfig(kubeClientConfig)
+		if err != nil {
+			staticPodCollectorErr = err
+			return
+		}
+		operatorNamespaces, err := staticpods.DiscoverOperatorNamespaces(ctx, discoveryClient, dynamicClient)
+		if err != nil {
+			staticPodCollectorErr = err
+			return
+		}
 
-	return &staticPodFailure{
-		namespace:      matches[1],
-		node:           matches[3],
-		revision:       revision,
-		failureMessage: message,
-	}, nil
+		staticPodCollector, staticPodCollectorErr = staticpods.NewCollector(ctx, kubeClient, operatorNamespaces)
+	})
+	return staticPodCollector, staticPodCollectorErr
 }
 
-func testStaticPodLifecycleFailure(events monitorapi.Intervals, kubeClientConfig *rest.Config, testSuite string) []*junitapi.JUnitTestCase {
+func testStaticPodLifecycleFailure(events monitorapi.Intervals, kubeClientConfig *rest.Config, testSuite string, artifactDir string) []*junitapi.JUnitTestCase {
 	ctx := context.TODO()
 	const testName = `[sig-node] static pods should start after being created`
-	failures := []string{}
-
-	kubeClient, err := kubernetes.NewForConfig(kubeClientConfig)
+	const fallbackTestName = `[sig-node] static pod fallback should recover the node`
+	const budgetTestName = `[sig-node] static pods should recover within budget`
+	budgets := config.DefaultStaticPodBudgets
+	testCases := []*junitapi.JUnitTestCase{}
+	fallbackFailures := []string{}
+	slowRecoveries := []string{}
+
+	collector, err := getStaticPodCollector(kubeClientConfig)
 	if err != nil {
 		return []*junitapi.JUnitTestCase{
 			{
@@ -63,114 +88,140 @@ func testStaticPodLifecycleFailure(events monitorapi.Intervals, kubeClientConfig
 		}
 	}
 
-	staticPodNamespaces := []string{
-		"openshift-etcd-operator",
-		"openshift-kube-apiserver-operator",
-		"openshift-kube-controller-manager-operator",
-		"openshift-kube-scheduler-operator",
-	}
-	staticPodFailures := []staticPodFailure{}
-	for _, ns := range staticPodNamespaces {
-		// we need to get all the events from the cluster, so we cannot use the monitor events.
-		events, err := kubeClient.EventsV1().Events(ns).List(ctx, metav1.ListOptions{})
-		if err != nil {
-			failures = append(failures, err.Error())
-			continue
+	configClient, err := configclient.NewForConfig(kubeClientConfig)
+	if err != nil {
+		return []*junitapi.JUnitTestCase{
+			{
+				Name: testName,
+				FailureOutput: &junitapi.FailureOutput{
+					Output: err.Error(),
+				},
+				SystemOut: err.Error(),
+			},
 		}
+	}
+	isSNO := false
+	if infra, err := configClient.ConfigV1().Infrastructures().Get(ctx, "cluster", metav1.GetOptions{}); err == nil {
+		isSNO = infra.Status.ControlPlaneTopology == configv1.SingleReplicaTopologyMode
+	}
 
-		for _, event := range events.Items {
-			if event.Reason == "OperatorStatusChanged" { // skip the clusteroperator status change event.
-				continue
-			}
-			if !strings.Contains(event.Note, "static pod lifecycle failure") {
-				continue
-			}
-
-			staticPodFailure, err := staticPodFailureFromMessage(event.Note)
-			if err != nil {
-				failures = append(failures, fmt.Sprintf("%v", err))
-				continue
+	allFailures := collector.FailuresSince(time.Time{})
+	if len(allFailures) > 0 {
+		if kubeClient, err := kubernetes.NewForConfig(kubeClientConfig); err == nil {
+			artifacts := staticpods.BuildFailureArtifacts(ctx, kubeClient, collector, allFailures)
+			if err := staticpods.WriteFailureArtifacts(artifactDir, artifacts); err != nil {
+				fmt.Printf("test %s: failed to write static-pod-failures.json: %v\n", testName, err)
 			}
-			staticPodFailures = append(staticPodFailures, *staticPodFailure)
 		}
 	}
 
 	// now check each failure to see if we eventually reached the level.  If we eventually reached the level
 	// then don't flag it
-	for _, staticPodFailure := range staticPodFailures {
-		events, err := kubeClient.EventsV1().Events(staticPodFailure.namespace).List(ctx, metav1.ListOptions{})
-		if err != nil {
-			failures = append(failures, err.Error())
-			continue
-		}
-		foundEventForProperRevision := false
-		for _, event := range events.Items {
-			isRevisionUpdate := event.Reason == "NodeCurrentRevisionChanged"
-			isForNode := strings.Contains(event.Note, staticPodFailure.node)
-			matches := regexp.MustCompile("to ([0-9]+) because static pod is ready").FindStringSubmatch(event.Note)
-			if len(matches) == 2 {
-				reachedRevision, _ := strconv.ParseInt(matches[1], 0, 64)
-				if isRevisionUpdate && isForNode && reachedRevision == staticPodFailure.revision {
-					// If we reach the level eventually, don't fail the test. We might choose to add an "it's slow" test, but
-					// it hasn't failed. It might be possible to go directly to a later revision, and if we want to account for
-					// that, the above could be changed to >= instead of equality.
-					foundEventForProperRevision = true
-				}
-			}
+	for _, staticPodFailure := range allFailures {
+		reachedAt, foundEventForProperRevision := collector.LookupRevisionReached(staticPodFailure.Namespace, staticPodFailure.Node, staticPodFailure.Revision)
+
+		fallbackAttempted, fallbackSucceeded := false, false
+		if isSNO {
+			fallbackAttempted, fallbackSucceeded = collector.LookupFallback(staticPodFailure.Namespace, staticPodFailure.Node)
 		}
 
-		// We are suspecting events API and core API are not returning the same events. Double check here.
-		// For debugging purpose
-		if !foundEventForProperRevision {
-			// Log the events API events
-			eventString, err := json.Marshal(events)
-			if err == nil {
-				fmt.Printf("test %s with failure message '%s' failed: corresponding events from events API %s\n", testName, staticPodFailure.failureMessage, eventString)
+		recovered := false
+		var timeToRecovery time.Duration
+		switch {
+		case foundEventForProperRevision:
+			// the failing revision was eventually reached, whether or not a fallback pod took over in the
+			// meantime: not a hard failure.
+			recovered = true
+			timeToRecovery = reachedAt.Sub(staticPodFailure.Time)
+		case fallbackAttempted && fallbackSucceeded:
+			// single node has no other master to fail over to; the fallback pod restored steady state even
+			// though the node never came back up on the originally failing revision.
+			recovered = true
+			if recoveredAt, ok := collector.FallbackRecoveredAt(staticPodFailure.Namespace, staticPodFailure.Node); ok {
+				timeToRecovery = recoveredAt.Sub(staticPodFailure.Time)
 			}
-			coreEvents, err := kubeClient.CoreV1().Events(staticPodFailure.namespace).List(ctx, metav1.ListOptions{})
-			if err == nil {
-				for _, event := range coreEvents.Items {
-					isRevisionUpdate := event.Reason == "NodeCurrentRevisionChanged"
-					isForNode := strings.Contains(event.Message, staticPodFailure.node)
-					matches := regexp.MustCompile("to ([0-9]+) because static pod is ready").FindStringSubmatch(event.Message)
-					if len(matches) == 2 {
-						reachedRevision, _ := strconv.ParseInt(matches[1], 0, 64)
-						if isRevisionUpdate && isForNode && reachedRevision == staticPodFailure.revision {
-							// Found the event in events returned from core API
-							foundEventForProperRevision = true
-							fmt.Printf("test %s with failure message '%s' recovered\n", testName, staticPodFailure.failureMessage)
-
-							// Log the core API events
-							eventString, err = json.Marshal(coreEvents)
-							if err == nil {
-								fmt.Printf("test %s recovered: corresponding events from core API %s\n", testName, eventString)
-							}
-						}
-					}
-				}
+		case fallbackAttempted:
+			fallbackFailures = append(fallbackFailures, fmt.Sprintf("%s: fallback to last-known-good revision did not restore steady state", staticPodFailure.FailureMessage))
+		}
+
+		if recovered && timeToRecovery > 0 {
+			if budget := budgets.Budget(staticPodFailure.Namespace); timeToRecovery > budget {
+				slowRecoveries = append(slowRecoveries, fmt.Sprintf("%s: recovered in %s, budget was %s", staticPodFailure.FailureMessage, timeToRecovery, budget))
 			}
 		}
 
-		if !foundEventForProperRevision {
-			failures = append(failures, staticPodFailure.failureMessage)
+		properties := []junitapi.JUnitProperty{
+			{Name: "namespace", Value: staticPodFailure.Namespace},
+			{Name: "node", Value: staticPodFailure.Node},
+			{Name: "revision", Value: fmt.Sprintf("%d", staticPodFailure.Revision)},
+			{Name: "wait", Value: staticPodFailure.WaitDuration.String()},
+			{Name: "recovered", Value: fmt.Sprintf("%t", recovered)},
+		}
+		if foundEventForProperRevision {
+			properties = append(properties, junitapi.JUnitProperty{Name: "revisionReachedAt", Value: reachedAt.UTC().Format(time.RFC3339)})
+		}
+		if recovered && timeToRecovery > 0 {
+			properties = append(properties, junitapi.JUnitProperty{Name: "timeToRecovery", Value: timeToRecovery.String()})
 		}
-	}
 
-	if len(failures) > 0 {
-		return []*junitapi.JUnitTestCase{
-			{
-				Name: testName,
+		testCase := &junitapi.JUnitTestCase{
+			Name:       testName,
+			Properties: properties,
+			SystemOut:  staticPodFailure.FailureMessage,
+		}
+		if recovered {
+			// a recovered failure is reported as a flake: a failing case alongside the passing one.
+			testCases = append(testCases, &junitapi.JUnitTestCase{
+				Name:       testName,
+				Properties: properties,
+				SystemOut:  staticPodFailure.FailureMessage,
 				FailureOutput: &junitapi.FailureOutput{
-					Output: strings.Join(failures, "\n"),
+					Output: staticPodFailure.FailureMessage,
 				},
-				SystemOut: strings.Join(failures, "\n"),
-			},
+			})
+		} else {
+			testCase.FailureOutput = &junitapi.FailureOutput{
+				Output: staticPodFailure.FailureMessage,
+			}
 		}
+		testCases = append(testCases, testCase)
 	}
 
-	return []*junitapi.JUnitTestCase{
-		{
+	if len(testCases) == 0 {
+		testCases = append(testCases, &junitapi.JUnitTestCase{
 			Name: testName,
-		},
+		})
+	}
+
+	if len(slowRecoveries) > 0 {
+		testCases = append(testCases, &junitapi.JUnitTestCase{
+			Name: budgetTestName,
+			FailureOutput: &junitapi.FailureOutput{
+				Output: strings.Join(slowRecoveries, "\n"),
+			},
+			SystemOut: strings.Join(slowRecoveries, "\n"),
+		})
+	} else {
+		testCases = append(testCases, &junitapi.JUnitTestCase{
+			Name: budgetTestName,
+		})
 	}
+
+	if isSNO {
+		if len(fallbackFailures) > 0 {
+			testCases = append(testCases, &junitapi.JUnitTestCase{
+				Name: fallbackTestName,
+				FailureOutput: &junitapi.FailureOutput{
+					Output: strings.Join(fallbackFailures, "\n"),
+				},
+				SystemOut: strings.Join(fallbackFailures, "\n"),
+			})
+		} else {
+			testCases = append(testCases, &junitapi.JUnitTestCase{
+				Name: fallbackTestName,
+			})
+		}
+	}
+
+	return testCases
 }
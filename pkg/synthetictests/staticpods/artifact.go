@@ -0,0 +1,111 @@
+package staticpods
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxRecentRevisionEvents caps how many NodeCurrentRevisionChanged events are embedded per failure
+// so the artifact stays readable for a long-running rollout.
+const maxRecentRevisionEvents = 20
+
+// podNamePrefix derives the static pod name prefix an operand namespace's manifests are installed
+// under, e.g. "openshift-kube-apiserver" -> "kube-apiserver". Every known static-pod operand
+// namespace follows this "openshift-<component>" convention, so this holds for any operator
+// discovered at runtime, not just the four known today.
+func podNamePrefix(operandNamespace string) string {
+	return strings.TrimPrefix(operandNamespace, "openshift-")
+}
+
+// FailureArtifact is the root-cause information gathered for a single StaticPodFailure, written
+// out alongside the JUnit report so CI jobs automatically capture it on a regression instead of
+// only logging it to stdout.
+type FailureArtifact struct {
+	Namespace                   string                   `json:"namespace"`
+	Node                        string                   `json:"node"`
+	Revision                    int64                    `json:"revision"`
+	FailureMessage              string                   `json:"failureMessage"`
+	Time                        time.Time                `json:"time"`
+	RecentRevisionEvents        []RevisionEvent          `json:"recentRevisionEvents,omitempty"`
+	StaticPodRevisionAnnotation string                   `json:"staticPodRevisionAnnotation,omitempty"`
+	ContainerStatuses           []corev1.ContainerStatus `json:"containerStatuses,omitempty"`
+	KubeletPodLogs              string                   `json:"kubeletPodLogs,omitempty"`
+	GatherErrors                []string                 `json:"gatherErrors,omitempty"`
+}
+
+// BuildFailureArtifacts gathers a FailureArtifact for each of the given failures: the installer
+// event history the Collector already has, plus a live snapshot of the static pod (annotations,
+// container statuses) and its kubelet logs, fetched through the API server proxy.
+func BuildFailureArtifacts(ctx context.Context, kubeClient kubernetes.Interface, collector *Collector, failures []StaticPodFailure) []FailureArtifact {
+	artifacts := make([]FailureArtifact, 0, len(failures))
+	for _, failure := range failures {
+		artifact := FailureArtifact{
+			Namespace:            failure.Namespace,
+			Node:                 failure.Node,
+			Revision:             failure.Revision,
+			FailureMessage:       failure.FailureMessage,
+			Time:                 failure.Time,
+			RecentRevisionEvents: collector.RecentRevisionEvents(failure.Namespace, failure.Node, maxRecentRevisionEvents),
+		}
+
+		// failure.Namespace is already the operand namespace, parsed out of the failure message.
+		operandNamespace := failure.Namespace
+		podName := fmt.Sprintf("%s-%s", podNamePrefix(operandNamespace), failure.Node)
+
+		pod, err := kubeClient.CoreV1().Pods(operandNamespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			artifact.GatherErrors = append(artifact.GatherErrors, fmt.Sprintf("get pod %s/%s: %v", operandNamespace, podName, err))
+		} else {
+			artifact.StaticPodRevisionAnnotation = pod.Annotations["revision"]
+			artifact.ContainerStatuses = pod.Status.ContainerStatuses
+		}
+
+		logs, err := fetchKubeletPodLogs(ctx, kubeClient, failure.Node)
+		if err != nil {
+			artifact.GatherErrors = append(artifact.GatherErrors, fmt.Sprintf("fetch kubelet pod logs on %s: %v", failure.Node, err))
+		} else {
+			artifact.KubeletPodLogs = logs
+		}
+
+		artifacts = append(artifacts, artifact)
+	}
+	return artifacts
+}
+
+// fetchKubeletPodLogs fetches the kubelet's /logs/pods endpoint on node through the API server's
+// node proxy, the same path `oc adm node-logs` uses.
+func fetchKubeletPodLogs(ctx context.Context, kubeClient kubernetes.Interface, node string) (string, error) {
+	raw, err := kubeClient.CoreV1().RESTClient().Get().
+		Resource("nodes").
+		Name(fmt.Sprintf("%s:10250", node)).
+		SubResource("proxy").
+		Suffix("logs/pods/").
+		DoRaw(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// WriteFailureArtifacts writes the given artifacts to static-pod-failures.json under artifactDir.
+// It is a no-op when there are no artifacts, so a clean run doesn't leave an empty file behind.
+func WriteFailureArtifacts(artifactDir string, artifacts []FailureArtifact) error {
+	if len(artifacts) == 0 {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(artifacts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(artifactDir, "static-pod-failures.json"), data, 0644)
+}
@@ -0,0 +1,355 @@
+// Package staticpods provides a shared, informer-backed view of static pod installer events
+// (lifecycle failures and revision rollouts) for the etcd, kube-apiserver, kube-controller-manager
+// and kube-scheduler operators. It replaces the historical pattern of re-listing events out of the
+// live API for every synthetic test that wants to reason about static pod rollouts.
+package staticpods
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// staticPodFailureRegex pulls the namespace/revision/node out of messages like
+// `static pod lifecycle failure - static pod: "etcd" in namespace: "openshift-etcd" for revision: 6 on node: "ovirt10-gh8t5-master-2" didn't show up, waited: 2m30s`
+var staticPodFailureRegex = regexp.MustCompile(
+	`static pod lifecycle failure - static pod: ".*" in namespace: "(.*)" for revision: (\d+) on node: "(.*)" didn't show up, waited: (.*)`)
+
+// parseStaticPodFailure parses a "static pod lifecycle failure" event message into a StaticPodFailure.
+func parseStaticPodFailure(message string, t time.Time) (*StaticPodFailure, error) {
+	matches := staticPodFailureRegex.FindStringSubmatch(message)
+	if len(matches) != 5 {
+		return nil, fmt.Errorf("wrong number of matches: %v", matches)
+	}
+	revision, err := strconv.ParseInt(matches[2], 0, 64)
+	if err != nil {
+		return nil, err
+	}
+	// the wait duration is best-effort: a message we can't parse the tail of still counts as a failure.
+	waitDuration, _ := time.ParseDuration(strings.TrimSpace(matches[4]))
+
+	return &StaticPodFailure{
+		Namespace:      matches[1],
+		Node:           matches[3],
+		Revision:       revision,
+		WaitDuration:   waitDuration,
+		FailureMessage: message,
+		Time:           t,
+	}, nil
+}
+
+// revisionReachedRegex pulls the revision a NodeCurrentRevisionChanged event settled on, e.g.
+// `node "master-0" moved to 6 because static pod is ready`. There is no structured node field on
+// these events, so - matching the polling code this package replaces - the node is identified by a
+// plain substring match against the message rather than a parsed field.
+var revisionReachedRegex = regexp.MustCompile(`to ([0-9]+) because static pod is ready`)
+
+// StaticPodFailure is a single "static pod lifecycle failure" event parsed off the operator's
+// status reporting.
+type StaticPodFailure struct {
+	Namespace      string
+	Node           string
+	Revision       int64
+	WaitDuration   time.Duration
+	FailureMessage string
+	Time           time.Time
+}
+
+// revisionReachedEvent records a NodeCurrentRevisionChanged event that reached a given revision.
+// Like the polling code this package replaces, node identity is confirmed with a substring match
+// against message rather than a parsed field, since the event carries no such structured field.
+type revisionReachedEvent struct {
+	namespace string
+	message   string
+	revision  int64
+	time      time.Time
+}
+
+type fallbackEvent struct {
+	namespace string
+	message   string
+	succeeded bool
+	time      time.Time
+}
+
+// RevisionEvent is a raw NodeCurrentRevisionChanged event, kept around so must-gather artifacts
+// can show the rollout history leading up to a failure rather than just the final outcome.
+type RevisionEvent struct {
+	Namespace string
+	Message   string
+	Time      time.Time
+}
+
+// Collector watches events.v1 (and, for clusters still emitting only core/v1 events, core/v1) across
+// a fixed set of namespaces and builds an in-memory index of revision rollouts and lifecycle
+// failures. synthetictests creates a single Collector on the first evaluation of its static pod
+// test and shares it from then on, instead of each evaluation re-listing events itself; the
+// informers' initial list picks up the events the API server still holds from before that point.
+type Collector struct {
+	lock sync.Mutex
+
+	operandNamespaces map[string]string
+	revisionReached   []revisionReachedEvent
+	revisionEvents    []RevisionEvent
+	failures          []StaticPodFailure
+	fallbacks         []fallbackEvent
+	seen              map[string]struct{}
+}
+
+// NewCollector creates a Collector and starts informers against both namespaces of each given
+// OperatorNamespaces: the operator namespace, where "static pod lifecycle failure" events are
+// reported, and the operand namespace, where the installer reports NodeCurrentRevisionChanged and
+// fallback events against the static pods themselves. It blocks until the informers' initial
+// caches have synced.
+func NewCollector(ctx context.Context, kubeClient kubernetes.Interface, namespaces []OperatorNamespaces) (*Collector, error) {
+	c := &Collector{
+		operandNamespaces: map[string]string{},
+		seen:              map[string]struct{}{},
+	}
+
+	watched := map[string]struct{}{}
+	for _, ns := range namespaces {
+		c.operandNamespaces[ns.OperatorNamespace] = ns.OperandNamespace
+
+		for _, watchNamespace := range []string{ns.OperatorNamespace, ns.OperandNamespace} {
+			if _, ok := watched[watchNamespace]; ok {
+				continue
+			}
+			watched[watchNamespace] = struct{}{}
+
+			if err := c.watchNamespace(ctx, kubeClient, watchNamespace); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// watchNamespace starts the events.v1 and core/v1 event informers for a single namespace.
+func (c *Collector) watchNamespace(ctx context.Context, kubeClient kubernetes.Interface, namespace string) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 0, informers.WithNamespace(namespace))
+
+	// Repeated identical events are aggregated by the API server onto the same object (bumping
+	// series.count via an Update rather than creating a new object), so both Add and Update must
+	// be wired up or every occurrence after the first is silently dropped.
+	eventsInformer := factory.Events().V1().Events().Informer()
+	if _, err := eventsInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.handleEventsV1,
+		UpdateFunc: func(_, newObj interface{}) { c.handleEventsV1(newObj) },
+	}); err != nil {
+		return fmt.Errorf("failed to register events.v1 handler for namespace %q: %w", namespace, err)
+	}
+
+	coreEventsInformer := factory.Core().V1().Events().Informer()
+	if _, err := coreEventsInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.handleCoreV1Event,
+		UpdateFunc: func(_, newObj interface{}) { c.handleCoreV1Event(newObj) },
+	}); err != nil {
+		return fmt.Errorf("failed to register core/v1 events handler for namespace %q: %w", namespace, err)
+	}
+
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+	return nil
+}
+
+func (c *Collector) handleEventsV1(obj interface{}) {
+	event, ok := obj.(*eventsv1.Event)
+	if !ok {
+		return
+	}
+	seriesCount := int32(1)
+	if event.Series != nil {
+		seriesCount = event.Series.Count
+	}
+	if !c.markSeen(string(event.Regarding.UID), seriesCount) {
+		return
+	}
+	c.recordEvent(event.Namespace, event.Reason, event.Note, event.EventTime.Time)
+}
+
+func (c *Collector) handleCoreV1Event(obj interface{}) {
+	event, ok := obj.(*corev1.Event)
+	if !ok {
+		return
+	}
+	seriesCount := int32(1)
+	if event.Series != nil {
+		seriesCount = event.Series.Count
+	}
+	if !c.markSeen(string(event.InvolvedObject.UID), seriesCount) {
+		return
+	}
+	c.recordEvent(event.Namespace, event.Reason, event.Message, event.LastTimestamp.Time)
+}
+
+// markSeen dedups events.v1 and core/v1 deliveries of the same underlying event, which the API
+// server otherwise reports twice as separate watch streams.
+func (c *Collector) markSeen(regardingUID string, seriesCount int32) bool {
+	key := fmt.Sprintf("%s/%d", regardingUID, seriesCount)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.seen[key]; ok {
+		return false
+	}
+	c.seen[key] = struct{}{}
+	return true
+}
+
+func (c *Collector) recordEvent(namespace, reason, message string, t time.Time) {
+	switch {
+	case reason != "OperatorStatusChanged" && strings.Contains(message, "static pod lifecycle failure"):
+		failure, err := parseStaticPodFailure(message, t)
+		if err != nil {
+			return
+		}
+
+		c.lock.Lock()
+		c.failures = append(c.failures, *failure)
+		c.lock.Unlock()
+
+	case reason == "NodeCurrentRevisionChanged":
+		c.lock.Lock()
+		c.revisionEvents = append(c.revisionEvents, RevisionEvent{Namespace: namespace, Message: message, Time: t})
+		c.lock.Unlock()
+
+		matches := revisionReachedRegex.FindStringSubmatch(message)
+		if len(matches) != 2 {
+			return
+		}
+		revision, err := strconv.ParseInt(matches[1], 0, 64)
+		if err != nil {
+			return
+		}
+
+		c.lock.Lock()
+		c.revisionReached = append(c.revisionReached, revisionReachedEvent{
+			namespace: namespace,
+			message:   message,
+			revision:  revision,
+			time:      t,
+		})
+		c.lock.Unlock()
+
+	case reason == "StaticPodFallbackRevisionSucceeded" || reason == "StaticPodFallbackRevisionFailed":
+		c.lock.Lock()
+		c.fallbacks = append(c.fallbacks, fallbackEvent{
+			namespace: namespace,
+			message:   message,
+			succeeded: reason == "StaticPodFallbackRevisionSucceeded",
+			time:      t,
+		})
+		c.lock.Unlock()
+	}
+}
+
+// LookupRevisionReached reports whether, and when, the given node was observed reaching the given
+// revision for the operator in namespace. As with the polling code this package replaces, a node is
+// considered a match if its name appears anywhere in the event message, since
+// NodeCurrentRevisionChanged events carry no structured node field.
+func (c *Collector) LookupRevisionReached(namespace, node string, revision int64) (time.Time, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	found := false
+	var reachedAt time.Time
+	for _, e := range c.revisionReached {
+		if e.namespace != namespace || e.revision != revision || !strings.Contains(e.message, node) {
+			continue
+		}
+		if !found || e.time.Before(reachedAt) {
+			reachedAt = e.time
+			found = true
+		}
+	}
+	return reachedAt, found
+}
+
+// LookupFallback reports whether a last-known-good-revision fallback was attempted for namespace/node
+// and, if so, whether it succeeded in restoring the static pod.
+func (c *Collector) LookupFallback(namespace, node string) (attempted, succeeded bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, f := range c.fallbacks {
+		if f.namespace != namespace || !strings.Contains(f.message, node) {
+			continue
+		}
+		attempted = true
+		if f.succeeded {
+			succeeded = true
+		}
+	}
+	return attempted, succeeded
+}
+
+// FallbackRecoveredAt returns when a last-known-good-revision fallback for namespace/node was
+// observed to succeed, so callers can measure time-to-recovery on the fallback path the same way
+// they do for a normal revision rollout.
+func (c *Collector) FallbackRecoveredAt(namespace, node string) (time.Time, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, f := range c.fallbacks {
+		if f.namespace == namespace && f.succeeded && strings.Contains(f.message, node) {
+			return f.time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// OperandNamespace returns the namespace the static-pod operator in operatorNamespace installs its
+// manifests into, as discovered from that operator's custom resource.
+func (c *Collector) OperandNamespace(operatorNamespace string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	ns, ok := c.operandNamespaces[operatorNamespace]
+	return ns, ok
+}
+
+// RecentRevisionEvents returns up to limit of the most recent NodeCurrentRevisionChanged events
+// observed for namespace/node, oldest first.
+func (c *Collector) RecentRevisionEvents(namespace, node string, limit int) []RevisionEvent {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	matching := []RevisionEvent{}
+	for _, e := range c.revisionEvents {
+		if e.Namespace != namespace || !strings.Contains(e.Message, node) {
+			continue
+		}
+		matching = append(matching, e)
+	}
+	if len(matching) > limit {
+		matching = matching[len(matching)-limit:]
+	}
+	return matching
+}
+
+// FailuresSince returns every lifecycle failure observed at or after t, in the order they were seen.
+func (c *Collector) FailuresSince(t time.Time) []StaticPodFailure {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	failures := make([]StaticPodFailure, 0, len(c.failures))
+	for _, f := range c.failures {
+		if f.Time.Before(t) {
+			continue
+		}
+		failures = append(failures, f)
+	}
+	return failures
+}
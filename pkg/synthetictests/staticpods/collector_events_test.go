@@ -0,0 +1,136 @@
+package staticpods
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newTestCollector() *Collector {
+	return &Collector{
+		seen: map[string]struct{}{},
+	}
+}
+
+// TestRecordEventIndexesRevisionReachedByNode deliberately uses a NodeCurrentRevisionChanged
+// message whose wording differs from other tests in this file: LookupRevisionReached matches by
+// plain substring, not a parsed node field, so it must not depend on one particular phrasing.
+func TestRecordEventIndexesRevisionReachedByNode(t *testing.T) {
+	c := newTestCollector()
+	t1 := time.Now()
+
+	c.recordEvent("openshift-etcd-operator", "NodeCurrentRevisionChanged", `static pod on master-0 advanced to 6 because static pod is ready`, t1)
+
+	if got, ok := c.LookupRevisionReached("openshift-etcd-operator", "master-0", 6); !ok || !got.Equal(t1) {
+		t.Fatalf("expected revision reached for master-0 at %s, got %s (found=%v)", t1, got, ok)
+	}
+	if _, ok := c.LookupRevisionReached("openshift-etcd-operator", "master-1", 6); ok {
+		t.Fatalf("did not expect a revision reached match for a different node")
+	}
+	if _, ok := c.LookupRevisionReached("openshift-etcd-operator", "master-0", 7); ok {
+		t.Fatalf("did not expect a revision reached match for a different revision")
+	}
+}
+
+func TestRecordEventTracksFallbackByNode(t *testing.T) {
+	c := newTestCollector()
+
+	c.recordEvent("openshift-etcd-operator", "StaticPodFallbackRevisionSucceeded", `fell back to last-known-good revision for master-0`, time.Now())
+
+	attempted, succeeded := c.LookupFallback("openshift-etcd-operator", "master-0")
+	if !attempted || !succeeded {
+		t.Fatalf("expected a successful fallback for master-0, got attempted=%v succeeded=%v", attempted, succeeded)
+	}
+	if attempted, _ := c.LookupFallback("openshift-etcd-operator", "master-1"); attempted {
+		t.Fatalf("did not expect a fallback match for a different node")
+	}
+}
+
+func TestFallbackRecoveredAtReturnsSuccessTime(t *testing.T) {
+	c := newTestCollector()
+	succeededAt := time.Now()
+
+	c.recordEvent("openshift-etcd-operator", "StaticPodFallbackRevisionFailed", `fell back to last-known-good revision for master-0`, succeededAt.Add(-time.Minute))
+	c.recordEvent("openshift-etcd-operator", "StaticPodFallbackRevisionSucceeded", `fell back to last-known-good revision for master-0`, succeededAt)
+
+	got, ok := c.FallbackRecoveredAt("openshift-etcd-operator", "master-0")
+	if !ok || !got.Equal(succeededAt) {
+		t.Fatalf("expected fallback recovered at %s, got %s (found=%v)", succeededAt, got, ok)
+	}
+	if _, ok := c.FallbackRecoveredAt("openshift-etcd-operator", "master-1"); ok {
+		t.Fatalf("did not expect a fallback recovery match for a different node")
+	}
+}
+
+func TestFailuresSinceFiltersByTime(t *testing.T) {
+	c := newTestCollector()
+	t1 := time.Now()
+	t2 := t1.Add(time.Minute)
+
+	c.recordEvent("openshift-etcd-operator", "", `static pod lifecycle failure - static pod: "etcd" in namespace: "openshift-etcd" for revision: 1 on node: "master-0" didn't show up, waited: 1m0s`, t1)
+	c.recordEvent("openshift-etcd-operator", "", `static pod lifecycle failure - static pod: "etcd" in namespace: "openshift-etcd" for revision: 2 on node: "master-1" didn't show up, waited: 1m0s`, t2)
+
+	if got := len(c.FailuresSince(time.Time{})); got != 2 {
+		t.Fatalf("expected 2 failures since the zero time, got %d", got)
+	}
+	if got := len(c.FailuresSince(t2)); got != 1 {
+		t.Fatalf("expected 1 failure at or after t2, got %d", got)
+	}
+}
+
+func TestHandleEventsV1DedupesBySeriesCount(t *testing.T) {
+	c := newTestCollector()
+	uid := types.UID("abc-123")
+	base := &eventsv1.Event{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-etcd-operator"},
+		Regarding:  corev1.ObjectReference{UID: uid},
+		Reason:     "NodeCurrentRevisionChanged",
+		Note:       `master-0 moved to 1 because static pod is ready`,
+		Series:     &eventsv1.EventSeries{Count: 1},
+	}
+
+	c.handleEventsV1(base)
+	c.handleEventsV1(base) // redelivery of the same object/count must not be recorded twice
+
+	if got, ok := c.LookupRevisionReached("openshift-etcd-operator", "master-0", 1); !ok {
+		t.Fatalf("expected revision reached to be recorded, got %s (found=%v)", got, ok)
+	}
+	if got := len(c.revisionReached); got != 1 {
+		t.Fatalf("expected the redelivery to be deduplicated into 1 recorded event, got %d", got)
+	}
+
+	updated := base.DeepCopy()
+	updated.Note = `master-0 moved to 2 because static pod is ready`
+	updated.Series = &eventsv1.EventSeries{Count: 2}
+	c.handleEventsV1(updated)
+
+	if _, ok := c.LookupRevisionReached("openshift-etcd-operator", "master-0", 2); !ok {
+		t.Fatalf("expected an Update with a bumped series count to be recorded as a new occurrence")
+	}
+}
+
+func TestHandleCoreV1EventDedupesBySeriesCount(t *testing.T) {
+	c := newTestCollector()
+	uid := types.UID("xyz-789")
+	base := &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "openshift-etcd-operator"},
+		InvolvedObject: corev1.ObjectReference{UID: uid},
+		Reason:         "NodeCurrentRevisionChanged",
+		Message:        `master-0 moved to 1 because static pod is ready`,
+		Series:         &corev1.EventSeries{Count: 1},
+	}
+
+	c.handleCoreV1Event(base)
+	c.handleCoreV1Event(base)
+
+	if _, ok := c.LookupRevisionReached("openshift-etcd-operator", "master-0", 1); !ok {
+		t.Fatalf("expected revision reached to be recorded from a core/v1 event")
+	}
+	if got := len(c.revisionReached); got != 1 {
+		t.Fatalf("expected the redelivery to be deduplicated into 1 recorded event, got %d", got)
+	}
+}
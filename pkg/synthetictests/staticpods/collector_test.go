@@ -0,0 +1,69 @@
+package staticpods
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseStaticPodFailure(t *testing.T) {
+	tests := []struct {
+		name            string
+		message         string
+		expectNamespace string
+		expectNode      string
+		expectRevision  int64
+		expectWait      time.Duration
+	}{
+		{
+			name:            "single digit revision",
+			message:         `static pod lifecycle failure - static pod: "etcd" in namespace: "openshift-etcd" for revision: 6 on node: "ovirt10-gh8t5-master-2" didn't show up, waited: 2m30s`,
+			expectNamespace: "openshift-etcd",
+			expectNode:      "ovirt10-gh8t5-master-2",
+			expectRevision:  6,
+			expectWait:      2*time.Minute + 30*time.Second,
+		},
+		{
+			name:            "multi digit revision",
+			message:         `static pod lifecycle failure - static pod: "kube-apiserver" in namespace: "openshift-kube-apiserver" for revision: 42 on node: "ip-10-0-1-2.ec2.internal" didn't show up, waited: 3m0s`,
+			expectNamespace: "openshift-kube-apiserver",
+			expectNode:      "ip-10-0-1-2.ec2.internal",
+			expectRevision:  42,
+			expectWait:      3 * time.Minute,
+		},
+		{
+			name:            "non-ASCII node name",
+			message:         `static pod lifecycle failure - static pod: "kube-scheduler" in namespace: "openshift-kube-scheduler" for revision: 7 on node: "wörker-üno" didn't show up, waited: 1m15s`,
+			expectNamespace: "openshift-kube-scheduler",
+			expectNode:      "wörker-üno",
+			expectRevision:  7,
+			expectWait:      1*time.Minute + 15*time.Second,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			failure, err := parseStaticPodFailure(test.message, time.Time{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if failure.Namespace != test.expectNamespace {
+				t.Errorf("expected namespace %q, got %q", test.expectNamespace, failure.Namespace)
+			}
+			if failure.Node != test.expectNode {
+				t.Errorf("expected node %q, got %q", test.expectNode, failure.Node)
+			}
+			if failure.Revision != test.expectRevision {
+				t.Errorf("expected revision %d, got %d", test.expectRevision, failure.Revision)
+			}
+			if failure.WaitDuration != test.expectWait {
+				t.Errorf("expected wait %s, got %s", test.expectWait, failure.WaitDuration)
+			}
+		})
+	}
+}
+
+func TestParseStaticPodFailureNoMatch(t *testing.T) {
+	if _, err := parseStaticPodFailure("not a static pod lifecycle failure message", time.Time{}); err == nil {
+		t.Fatal("expected an error for a non-matching message")
+	}
+}
@@ -0,0 +1,121 @@
+package staticpods
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+)
+
+// operatorGroup is the API group every static-pod operator's custom resource belongs to. Which
+// *kinds* within that group manage static pods is discovered at runtime via the API server's
+// discovery endpoint, rather than hard-coded, so a new OLM-installed static-pod operator is picked
+// up without a code change.
+const operatorGroup = "operator.openshift.io"
+
+// operatorNamespaceAnnotation and operandNamespaceAnnotation are a convention this package proposes
+// for self-describing static-pod operators: the operator namespace annotation identifies where the
+// operator reports its installer events, and the operand namespace annotation identifies where its
+// static pods run. No operator.openshift.io resource sets either of these today (etcds,
+// kubeapiservers, kubecontrollermanagers, kubeschedulers included) - discovery therefore always
+// falls back to fallbackOperatorNamespaces on a real cluster right now. The annotation path exists
+// so that a future static-pod operator can opt into being discovered without a code change here;
+// until one does, every operator is picked up only via the fallback list below.
+const operatorNamespaceAnnotation = "operator.openshift.io/operator-namespace"
+const operandNamespaceAnnotation = "operator.openshift.io/operand-namespace"
+
+// OperatorNamespaces pairs the namespace a static-pod operator reports events from with the
+// namespace its static pods actually run in. The two differ for every known operator today
+// (e.g. "openshift-etcd-operator" vs "openshift-etcd").
+type OperatorNamespaces struct {
+	OperatorNamespace string
+	OperandNamespace  string
+}
+
+// fallbackOperatorNamespaces is used when discovery finds nothing, e.g. because the discovery or
+// dynamic client couldn't reach the API server, so the previously hard-coded behavior is preserved
+// as a last resort rather than leaving the collector with no namespaces to watch at all.
+var fallbackOperatorNamespaces = []OperatorNamespaces{
+	{OperatorNamespace: "openshift-etcd-operator", OperandNamespace: "openshift-etcd"},
+	{OperatorNamespace: "openshift-kube-apiserver-operator", OperandNamespace: "openshift-kube-apiserver"},
+	{OperatorNamespace: "openshift-kube-controller-manager-operator", OperandNamespace: "openshift-kube-controller-manager"},
+	{OperatorNamespace: "openshift-kube-scheduler-operator", OperandNamespace: "openshift-kube-scheduler"},
+}
+
+// DiscoverOperatorNamespaces discovers every operator.openshift.io resource kind the API server
+// currently serves (not a fixed list of kinds) and lists each one, looking for instances that opt
+// into discovery via operatorNamespaceAnnotation and operandNamespaceAnnotation. As of this writing
+// no real static-pod operator sets those annotations, so this will find nothing on a real cluster
+// and fall back to fallbackOperatorNamespaces - the same hard-coded 4 operators watched before this
+// function existed. It is still worth discovering generically: a future static-pod operator that
+// adds the annotations is picked up with no code change here, whereas extending
+// fallbackOperatorNamespaces always requires one.
+func DiscoverOperatorNamespaces(ctx context.Context, discoveryClient discovery.DiscoveryInterface, dynamicClient dynamic.Interface) ([]OperatorNamespaces, error) {
+	resources, err := discoverOperatorGroupResources(discoveryClient)
+	if err != nil {
+		fmt.Printf("static-pod operator discovery: failed to list operator.openshift.io resource kinds, falling back to the hard-coded operator list: %v\n", err)
+		return fallbackOperatorNamespaces, nil
+	}
+
+	namespaces := []OperatorNamespaces{}
+	seen := map[OperatorNamespaces]struct{}{}
+
+	for _, gvr := range resources {
+		list, err := dynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			continue
+		}
+		for _, item := range list.Items {
+			annotations := item.GetAnnotations()
+			operatorNS, operandNS := annotations[operatorNamespaceAnnotation], annotations[operandNamespaceAnnotation]
+			if operatorNS == "" || operandNS == "" {
+				continue
+			}
+
+			entry := OperatorNamespaces{OperatorNamespace: operatorNS, OperandNamespace: operandNS}
+			if _, ok := seen[entry]; ok {
+				continue
+			}
+			seen[entry] = struct{}{}
+			namespaces = append(namespaces, entry)
+		}
+	}
+
+	if len(namespaces) == 0 {
+		fmt.Printf("static-pod operator discovery: no operator.openshift.io resource carried the %q/%q annotations, falling back to the hard-coded operator list\n",
+			operatorNamespaceAnnotation, operandNamespaceAnnotation)
+		return fallbackOperatorNamespaces, nil
+	}
+	return namespaces, nil
+}
+
+// discoverOperatorGroupResources asks the API server's discovery endpoint for every resource kind
+// currently served under operatorGroup. It tolerates a partial discovery failure (some other group
+// failing to respond), since that's expected on a degraded cluster and shouldn't prevent watching
+// the static-pod operators that did respond.
+func discoverOperatorGroupResources(discoveryClient discovery.DiscoveryInterface) ([]schema.GroupVersionResource, error) {
+	_, apiResourceLists, err := discoveryClient.ServerGroupsAndResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, err
+	}
+
+	resources := []schema.GroupVersionResource{}
+	for _, list := range apiResourceLists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil || gv.Group != operatorGroup {
+			continue
+		}
+		for _, apiResource := range list.APIResources {
+			// skip subresources like "etcds/status"
+			if strings.Contains(apiResource.Name, "/") {
+				continue
+			}
+			resources = append(resources, gv.WithResource(apiResource.Name))
+		}
+	}
+	return resources, nil
+}
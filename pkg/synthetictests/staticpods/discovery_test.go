@@ -0,0 +1,59 @@
+package staticpods
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// fakeDiscoveryClient implements only the subset of discovery.DiscoveryInterface this package
+// uses; every other method panics via the embedded nil interface if ever called.
+type fakeDiscoveryClient struct {
+	discovery.DiscoveryInterface
+	lists []*metav1.APIResourceList
+}
+
+func (f *fakeDiscoveryClient) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	return nil, f.lists, nil
+}
+
+func TestDiscoverOperatorGroupResourcesFiltersByGroupAndSubresources(t *testing.T) {
+	discoveryClient := &fakeDiscoveryClient{
+		lists: []*metav1.APIResourceList{
+			{
+				GroupVersion: "operator.openshift.io/v1",
+				APIResources: []metav1.APIResource{
+					{Name: "etcds"},
+					{Name: "etcds/status"},
+					{Name: "newstaticpodoperators"},
+				},
+			},
+			{
+				GroupVersion: "config.openshift.io/v1",
+				APIResources: []metav1.APIResource{
+					{Name: "infrastructures"},
+				},
+			},
+		},
+	}
+
+	resources, err := discoverOperatorGroupResources(discoveryClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[schema.GroupVersionResource]bool{
+		{Group: "operator.openshift.io", Version: "v1", Resource: "etcds"}:                  true,
+		{Group: "operator.openshift.io", Version: "v1", Resource: "newstaticpodoperators"}: true,
+	}
+	if len(resources) != len(want) {
+		t.Fatalf("expected %d resources, got %d: %v", len(want), len(resources), resources)
+	}
+	for _, r := range resources {
+		if !want[r] {
+			t.Errorf("unexpected resource discovered: %v", r)
+		}
+	}
+}
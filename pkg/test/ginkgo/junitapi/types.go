@@ -0,0 +1,51 @@
+package junitapi
+
+import "encoding/xml"
+
+// JUnitTestSuite represents a JUnit test report.
+type JUnitTestSuite struct {
+	XMLName  xml.Name `xml:"testsuite"`
+	Name     string   `xml:"name,attr"`
+	NumTests uint     `xml:"tests,attr"`
+
+	NumSkipped uint `xml:"skipped,attr"`
+	NumFailed  uint `xml:"failures,attr"`
+
+	Duration float64 `xml:"time,attr"`
+
+	TestCases []*JUnitTestCase `xml:"testcase"`
+	Children  []*JUnitTestSuite
+}
+
+// JUnitTestCase represents a single test case and its result.
+type JUnitTestCase struct {
+	Name      string  `xml:"name,attr"`
+	Classname string  `xml:"classname,attr"`
+	Duration  float64 `xml:"time,attr"`
+
+	FailureOutput *FailureOutput `xml:"failure,omitempty"`
+	SystemOut     string         `xml:"system-out,omitempty"`
+	SkipMessage   *SkipMessage   `xml:"skipped,omitempty"`
+
+	// Properties carries machine-readable key/value data about the test case that tooling
+	// downstream of the JUnit XML (e.g. sippy) can aggregate without re-parsing SystemOut. It
+	// mirrors the JUnit XML <properties><property name="..." value="..."/></properties> element.
+	Properties []JUnitProperty `xml:"properties>property,omitempty"`
+}
+
+// JUnitProperty is a single name/value pair attached to a JUnitTestCase.
+type JUnitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// FailureOutput contains details about a test failure.
+type FailureOutput struct {
+	Message string `xml:"message,attr"`
+	Output  string `xml:",chardata"`
+}
+
+// SkipMessage contains the reason a test was skipped.
+type SkipMessage struct {
+	Message string `xml:",chardata"`
+}